@@ -0,0 +1,62 @@
+//go:build !windows
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// lookupMAC resolves the hardware address for ip from the kernel's ARP/
+// neighbour cache. It reads /proc/net/arp directly (fast, no subprocess)
+// and falls back to `ip neigh` for platforms where /proc/net/arp is
+// absent or the entry hasn't been flushed to it yet.
+func lookupMAC(ip string) (string, error) {
+	if mac, err := lookupMACProc(ip); err == nil {
+		return mac, nil
+	}
+	return lookupMACIPNeigh(ip)
+}
+
+func lookupMACProc(ip string) (string, error) {
+	f, err := os.Open("/proc/net/arp")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Scan() // header line
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		// IP address, HW type, Flags, HW address, Mask, Device
+		if len(fields) < 4 {
+			continue
+		}
+		if fields[0] == ip {
+			mac := fields[3]
+			if mac == "00:00:00:00:00:00" {
+				return "", fmt.Errorf("no ARP entry for %s", ip)
+			}
+			return mac, nil
+		}
+	}
+	return "", fmt.Errorf("no ARP entry for %s", ip)
+}
+
+func lookupMACIPNeigh(ip string) (string, error) {
+	out, err := exec.Command("ip", "neigh", "show", ip).Output()
+	if err != nil {
+		return "", err
+	}
+	fields := strings.Fields(string(out))
+	for i, f := range fields {
+		if f == "lladdr" && i+1 < len(fields) {
+			return fields[i+1], nil
+		}
+	}
+	return "", fmt.Errorf("no neighbour entry for %s", ip)
+}
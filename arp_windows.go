@@ -0,0 +1,68 @@
+//go:build windows
+
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	modiphlpapi       = syscall.NewLazyDLL("iphlpapi.dll")
+	procGetIpNetTable = modiphlpapi.NewProc("GetIpNetTable")
+)
+
+// mibIPNetRow mirrors the MIB_IPNETROW struct from iphlpapi.h.
+type mibIPNetRow struct {
+	Index       uint32
+	PhysAddrLen uint32
+	PhysAddr    [8]byte
+	Addr        uint32
+	Type        uint32
+}
+
+// lookupMAC resolves the hardware address for ip from the Windows IP-to-
+// physical-address table (the ARP cache equivalent on this platform).
+func lookupMAC(ip string) (string, error) {
+	target := net.ParseIP(ip).To4()
+	if target == nil {
+		return "", fmt.Errorf("not an IPv4 address: %s", ip)
+	}
+
+	var size uint32
+	procGetIpNetTable.Call(0, uintptr(unsafe.Pointer(&size)), 0)
+	if size == 0 {
+		return "", fmt.Errorf("GetIpNetTable: unable to determine buffer size")
+	}
+
+	buf := make([]byte, size)
+	ret, _, _ := procGetIpNetTable.Call(
+		uintptr(unsafe.Pointer(&buf[0])),
+		uintptr(unsafe.Pointer(&size)),
+		1, // sorted
+	)
+	if ret != 0 {
+		return "", fmt.Errorf("GetIpNetTable failed: %d", ret)
+	}
+
+	numEntries := binary.LittleEndian.Uint32(buf[:4])
+	rows := buf[4:]
+	rowSize := int(unsafe.Sizeof(mibIPNetRow{}))
+
+	for i := uint32(0); i < numEntries; i++ {
+		offset := int(i) * rowSize
+		if offset+rowSize > len(rows) {
+			break
+		}
+		row := (*mibIPNetRow)(unsafe.Pointer(&rows[offset]))
+		if row.Addr == binary.LittleEndian.Uint32(target) && row.PhysAddrLen == 6 {
+			mac := row.PhysAddr[:6]
+			return fmt.Sprintf("%02x:%02x:%02x:%02x:%02x:%02x",
+				mac[0], mac[1], mac[2], mac[3], mac[4], mac[5]), nil
+		}
+	}
+	return "", fmt.Errorf("no ARP entry for %s", ip)
+}
@@ -0,0 +1,16 @@
+package main
+
+// enrichDevice fills in the MAC, Vendor, and Services fields for an
+// already-reachable device. mdnsServices maps IP to the DNS-SD records
+// collected by a single LAN-wide mDNS sweep (see queryMDNSServices); it
+// may be nil if that sweep failed or hasn't run.
+func enrichDevice(device *Device, mdnsServices map[string][]string) {
+	if mac, err := lookupMAC(device.IP); err == nil {
+		device.MAC = mac
+		device.Vendor = vendorForMAC(mac)
+	}
+
+	if svcs, ok := mdnsServices[device.IP]; ok {
+		device.Services = svcs
+	}
+}
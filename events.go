@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"go-network-check/monitor"
+)
+
+// monitorProbeInterval is how often the background monitor re-checks
+// watched devices between scans.
+const monitorProbeInterval = 30 * time.Second
+
+// netMonitor tracks watched devices' history and reachability
+// transitions across the life of the process; scanNetworkStream
+// registers every device that answers its probes with it, up to the
+// monitor's cap on watched devices.
+var netMonitor = monitor.New(monitorProbe, monitorProbeInterval)
+
+func monitorProbe(ip string) (online bool, rttMillis float64) {
+	device := pingDevice(ip)
+	return device.Status == "online", device.RTTMillis
+}
+
+// startMonitor launches the background re-probe loop and link-change
+// watcher. onLinkChange fires whenever a local interface or IP address
+// changes, so main can re-derive LocalIP/Subnet and kick off a rescan.
+func startMonitor(onLinkChange func()) {
+	netMonitor.Start(context.Background(), onLinkChange)
+}
+
+// handleEvents streams device_up/device_down/link_change events as
+// Server-Sent Events for as long as the client stays connected.
+func handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "Streaming not supported")
+		return
+	}
+
+	events, cancel := netMonitor.Subscribe()
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e := <-events:
+			data, err := json.Marshal(e)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "data: %s\n\n", data)
+			flusher.Flush()
+		}
+	}
+}
+
+// handleDeviceHistory serves GET /api/device/{ip}/history with the
+// rolling history monitor.Monitor has accumulated for ip.
+func handleDeviceHistory(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	path := strings.TrimPrefix(r.URL.Path, "/api/device/")
+	ip := strings.TrimSuffix(path, "/history")
+	if ip == "" || ip == path {
+		respondWithError(w, http.StatusNotFound, "Not found")
+		return
+	}
+
+	history, ok := netMonitor.History(ip)
+	if !ok {
+		respondWithError(w, http.StatusNotFound, "No history for device")
+		return
+	}
+
+	json.NewEncoder(w).Encode(history)
+}
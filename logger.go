@@ -0,0 +1,40 @@
+package main
+
+import (
+	"flag"
+	"log/slog"
+	"os"
+)
+
+var (
+	logLevelFlag  = flag.String("log-level", "info", "log level: debug, info, warn, error")
+	logFormatFlag = flag.String("log-format", "console", "log format: json or console")
+)
+
+// initLogger parses the -log-level/-log-format flags and installs the
+// resulting structured logger as the slog default. Call once from main
+// after flag.Parse().
+func initLogger() {
+	opts := &slog.HandlerOptions{Level: parseLogLevel(*logLevelFlag)}
+
+	var handler slog.Handler
+	if *logFormatFlag == "json" {
+		handler = slog.NewJSONHandler(os.Stdout, opts)
+	} else {
+		handler = slog.NewTextHandler(os.Stdout, opts)
+	}
+	slog.SetDefault(slog.New(handler))
+}
+
+func parseLogLevel(s string) slog.Level {
+	switch s {
+	case "debug":
+		return slog.LevelDebug
+	case "warn":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
@@ -1,43 +1,94 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"flag"
 	"fmt"
+	"log/slog"
 	"net"
 	"net/http"
+	"strconv"
 	"strings"
-	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"go-network-check/upnp"
 )
 
 type Device struct {
-	IP       string `json:"ip"`
-	Hostname string `json:"hostname"`
-	Status   string `json:"status"`
-	LastSeen string `json:"last_seen"`
+	IP           string   `json:"ip"`
+	Hostname     string   `json:"hostname"`
+	Status       string   `json:"status"`
+	LastSeen     string   `json:"last_seen"`
+	RTTMillis    float64  `json:"rtt_ms"`
+	TTL          int      `json:"ttl"`
+	ProbeMethod  string   `json:"probe_method"`
+	LossPercent  float64  `json:"loss_percent"`
+	MAC          string   `json:"mac,omitempty"`
+	Vendor       string   `json:"vendor,omitempty"`
+	Services     []string `json:"services,omitempty"`
+	FriendlyName string   `json:"friendly_name,omitempty"`
+	Model        string   `json:"model,omitempty"`
 }
 
 type NetworkStatus struct {
-	LocalIP    string   `json:"local_ip"`
-	Subnet     string   `json:"subnet"`
-	DeviceCount int     `json:"device_count"`
-	Devices    []Device `json:"devices"`
+	LocalIP     string   `json:"local_ip"`
+	Subnet      string   `json:"subnet"`
+	DeviceCount int      `json:"device_count"`
+	Devices     []Device `json:"devices"`
 }
 
 func main() {
-	http.HandleFunc("/api/network/status", handleNetworkStatus)
-	http.HandleFunc("/api/device/ping", handleDevicePing)
-	http.HandleFunc("/api/network/scan", handleNetworkScan)
-	http.HandleFunc("/", handleRoot)
-
-	fmt.Println("Starting Network Status API on :8080")
-	fmt.Println("Endpoints:")
-	fmt.Println("  GET  /api/network/status - Get quick network overview")
-	fmt.Println("  GET  /api/network/scan   - Scan network for devices")
-	fmt.Println("  POST /api/device/ping    - Ping specific device (JSON: {\"ip\": \"192.168.1.1\"})")
-	
-	if err := http.ListenAndServe(":8080", nil); err != nil {
-		fmt.Printf("Error starting server: %v\n", err)
+	flag.Parse()
+	initLogger()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/network/status", handleNetworkStatus)
+	mux.HandleFunc("/api/device/ping", handleDevicePing)
+	mux.HandleFunc("/api/network/scan", handleNetworkScan)
+	mux.HandleFunc("/api/network/scan/stream", handleNetworkScanStream)
+	mux.HandleFunc("/api/network/upnp", handleUPnPDiscover)
+	mux.HandleFunc("/api/events", handleEvents)
+	mux.HandleFunc("/api/device/", handleDeviceHistory)
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/", handleRoot)
+
+	var rescanning atomic.Bool
+	startMonitor(func() {
+		slog.Info("link change detected, local IP/subnet may have changed")
+		if !rescanning.CompareAndSwap(false, true) {
+			return
+		}
+		go func() {
+			defer rescanning.Store(false)
+			localIP, err := getLocalIP()
+			if err != nil {
+				slog.Warn("rescan after link change skipped", "error", err)
+				return
+			}
+			if _, err := scanNetwork(context.Background(), getSubnet(localIP), defaultScanWorkers, probeTimeout); err != nil {
+				slog.Warn("rescan after link change failed", "error", err)
+			}
+		}()
+	})
+
+	slog.Info("starting network status API", "addr", ":8080", "log_level", *logLevelFlag, "log_format", *logFormatFlag)
+	slog.Info("endpoints",
+		"GET /api/network/status", "quick network overview",
+		"GET /api/network/scan", "full network scan",
+		"GET /api/network/scan/stream", "stream scan results as NDJSON",
+		"GET /api/network/upnp", "discover UPnP Internet Gateway Devices",
+		"GET /api/events", "stream device/link events (SSE)",
+		"GET /api/device/{ip}/history", "a device's monitoring history",
+		"GET /metrics", "Prometheus metrics",
+		"POST /api/device/ping", "ping specific device (JSON body)",
+	)
+
+	if err := http.ListenAndServe(":8080", accessLogMiddleware(mux)); err != nil {
+		slog.Error("server exited", "error", err)
 	}
 }
 
@@ -69,7 +120,7 @@ func handleNetworkStatus(w http.ResponseWriter, r *http.Request) {
 	}
 
 	subnet := getSubnet(localIP)
-	
+
 	status := NetworkStatus{
 		LocalIP:     localIP,
 		Subnet:      subnet,
@@ -104,12 +155,21 @@ func handleNetworkScan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	subnet := getSubnet(localIP)
-	devices := scanNetwork(localIP)
+	cidr, workers, timeout, err := parseScanParams(r, getSubnet(localIP))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	devices, err := scanNetwork(r.Context(), cidr, workers, timeout)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
 	status := NetworkStatus{
 		LocalIP:     localIP,
-		Subnet:      subnet,
+		Subnet:      cidr,
 		DeviceCount: len(devices),
 		Devices:     devices,
 	}
@@ -117,6 +177,74 @@ func handleNetworkScan(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(status)
 }
 
+// handleNetworkScanStream behaves like handleNetworkScan but flushes one
+// Device as newline-delimited JSON as soon as its probe completes,
+// letting clients render progress across scans that take tens of
+// seconds on a large CIDR. Unlike handleNetworkScan it emits every
+// probed host, offline ones included.
+func handleNetworkScanStream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "Streaming not supported")
+		return
+	}
+
+	localIP, err := getLocalIP()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get local IP")
+		return
+	}
+
+	cidr, workers, timeout, err := parseScanParams(r, getSubnet(localIP))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	stream, err := scanNetworkStream(r.Context(), cidr, workers, timeout)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	enc := json.NewEncoder(w)
+	for device := range stream {
+		if err := enc.Encode(device); err != nil {
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// parseScanParams reads the ?cidr=, ?workers=, and ?timeout= (duration,
+// e.g. "500ms") query params shared by the scan endpoints, falling back
+// to defaultCIDR and package defaults when a param is absent.
+func parseScanParams(r *http.Request, defaultCIDR string) (cidr string, workers int, timeout time.Duration, err error) {
+	cidr = defaultCIDR
+	if v := r.URL.Query().Get("cidr"); v != "" {
+		cidr = v
+	}
+
+	workers = defaultScanWorkers
+	if v := r.URL.Query().Get("workers"); v != "" {
+		workers, err = strconv.Atoi(v)
+		if err != nil || workers <= 0 {
+			return "", 0, 0, fmt.Errorf("invalid workers value %q", v)
+		}
+	}
+
+	timeout = probeTimeout
+	if v := r.URL.Query().Get("timeout"); v != "" {
+		timeout, err = time.ParseDuration(v)
+		if err != nil || timeout <= 0 {
+			return "", 0, 0, fmt.Errorf("invalid timeout value %q", v)
+		}
+	}
+
+	return cidr, workers, timeout, nil
+}
+
 func handleDevicePing(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
@@ -143,6 +271,20 @@ func handleDevicePing(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(device)
 }
 
+func handleUPnPDiscover(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	devices, err := upnp.Discover(upnpDiscoveryTimeout)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "UPnP discovery failed")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"igds": devices,
+	})
+}
+
 func getLocalIP() (string, error) {
 	addrs, err := net.InterfaceAddrs()
 	if err != nil {
@@ -168,71 +310,6 @@ func getSubnet(ip string) string {
 	return ""
 }
 
-func scanNetwork(localIP string) []Device {
-	parts := strings.Split(localIP, ".")
-	if len(parts) != 4 {
-		return []Device{}
-	}
-
-	baseIP := fmt.Sprintf("%s.%s.%s", parts[0], parts[1], parts[2])
-	devices := []Device{}
-	var mu sync.Mutex
-	var wg sync.WaitGroup
-
-	// Scan first 50 IPs for reasonable performance
-	for i := 1; i <= 50; i++ {
-		wg.Add(1)
-		go func(i int) {
-			defer wg.Done()
-			ip := fmt.Sprintf("%s.%d", baseIP, i)
-			device := pingDevice(ip)
-			if device.Status == "online" {
-				mu.Lock()
-				devices = append(devices, device)
-				mu.Unlock()
-			}
-		}(i)
-	}
-
-	wg.Wait()
-	return devices
-}
-
-func pingDevice(ip string) Device {
-	device := Device{
-		IP:       ip,
-		Hostname: ip,
-		Status:   "offline",
-		LastSeen: "",
-	}
-
-	// Try to connect with a short timeout
-	conn, err := net.DialTimeout("tcp", ip+":80", 500*time.Millisecond)
-	if err == nil {
-		conn.Close()
-		device.Status = "online"
-		device.LastSeen = time.Now().Format(time.RFC3339)
-	} else {
-		// Try ICMP-style check via UDP
-		conn, err := net.DialTimeout("udp", ip+":53", 500*time.Millisecond)
-		if err == nil {
-			conn.Close()
-			device.Status = "online"
-			device.LastSeen = time.Now().Format(time.RFC3339)
-		}
-	}
-
-	if device.Status == "online" {
-		// Try to resolve hostname
-		hostnames, err := net.LookupAddr(ip)
-		if err == nil && len(hostnames) > 0 {
-			device.Hostname = hostnames[0]
-		}
-	}
-
-	return device
-}
-
 func respondWithError(w http.ResponseWriter, code int, message string) {
 	w.WriteHeader(code)
 	json.NewEncoder(w).Encode(map[string]string{"error": message})
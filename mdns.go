@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const mdnsServicesQuery = "_services._dns-sd._udp.local."
+
+// mdnsSweepTimeout bounds how long scanNetwork waits for DNS-SD replies
+// during its single LAN-wide mDNS sweep.
+const mdnsSweepTimeout = 1 * time.Second
+
+var mdnsAddr = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+
+// queryMDNSServices sends a single multicast DNS-SD service enumeration
+// query (RFC 6763 section 9) to the LAN and collects the PTR records
+// returned within timeout, keyed by the responding device's IP. It's
+// best-effort: most IoT gear, printers, and media devices respond, but
+// plenty of hosts stay silent. Called once per scan rather than once
+// per device to avoid flooding the segment with multicast traffic.
+func queryMDNSServices(timeout time.Duration) (map[string][]string, error) {
+	conn, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	query := dnsmessage.Message{
+		Header: dnsmessage.Header{ID: 0, RecursionDesired: false},
+		Questions: []dnsmessage.Question{
+			{
+				Name:  dnsmessage.MustNewName(mdnsServicesQuery),
+				Type:  dnsmessage.TypePTR,
+				Class: dnsmessage.ClassINET,
+			},
+		},
+	}
+	packed, err := query.Pack()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.WriteToUDP(packed, mdnsAddr); err != nil {
+		return nil, err
+	}
+
+	services := map[string]map[string]bool{}
+	deadline := time.Now().Add(timeout)
+	buf := make([]byte, 9000)
+	for {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			break
+		}
+		conn.SetReadDeadline(time.Now().Add(remaining))
+		n, src, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			break
+		}
+
+		var resp dnsmessage.Message
+		if err := resp.Unpack(buf[:n]); err != nil {
+			continue
+		}
+		for _, ans := range resp.Answers {
+			if ans.Header.Type != dnsmessage.TypePTR {
+				continue
+			}
+			ptr, ok := ans.Body.(*dnsmessage.PTRResource)
+			if !ok {
+				continue
+			}
+			host := src.IP.String()
+			if services[host] == nil {
+				services[host] = map[string]bool{}
+			}
+			services[host][ptr.PTR.String()] = true
+		}
+	}
+
+	out := make(map[string][]string, len(services))
+	for host, svcs := range services {
+		list := make([]string, 0, len(svcs))
+		for svc := range svcs {
+			list = append(list, svc)
+		}
+		out[host] = list
+	}
+	return out, nil
+}
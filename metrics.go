@@ -0,0 +1,37 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Metrics exported at GET /metrics for Prometheus/Grafana/Alertmanager
+// to scrape when running this service unattended.
+var (
+	probesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "network_check_probes_total",
+		Help: "Reachability probes attempted, partitioned by method and result.",
+	}, []string{"method", "result"})
+
+	probeRTTSeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "network_check_probe_rtt_seconds",
+		Help:    "Round-trip time of successful reachability probes.",
+		Buckets: prometheus.DefBuckets,
+	})
+
+	scanDurationSeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "network_check_scan_duration_seconds",
+		Help:    "Duration of full network scans.",
+		Buckets: prometheus.ExponentialBuckets(0.1, 2, 10),
+	})
+
+	devicesOnline = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "network_check_devices_online",
+		Help: "Number of devices online as of the last completed scan.",
+	})
+
+	lastScanTimestamp = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "network_check_last_scan_timestamp_seconds",
+		Help: "Unix timestamp of the last completed scan.",
+	})
+)
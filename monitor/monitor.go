@@ -0,0 +1,257 @@
+// Package monitor turns one-shot scan results into a live view of the
+// network: it periodically re-probes known devices, keeps rolling
+// history per device, and emits events when reachability or local link
+// state changes.
+package monitor
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// maxSamples bounds the RTT ring buffer kept per device.
+const maxSamples = 50
+
+// maxWatchedDevices bounds how many distinct IPs Watch will enroll for
+// background re-probing. Without a cap, a single ad-hoc scan of a
+// caller-supplied CIDR (see scanNetworkStream) could permanently enroll
+// thousands of hosts into the 30s probe loop with no way to evict them.
+const maxWatchedDevices = 1024
+
+// ProbeFunc re-checks a single device's reachability. It's supplied by
+// the caller so this package doesn't need to depend on how probing is
+// implemented (ICMP, TCP fallback, etc).
+type ProbeFunc func(ip string) (online bool, rttMillis float64)
+
+// Event describes a state transition pushed to subscribers of
+// GET /api/events.
+type Event struct {
+	Type      string `json:"type"` // "device_up", "device_down", or "link_change"
+	IP        string `json:"ip,omitempty"`
+	Timestamp string `json:"timestamp"`
+	Detail    string `json:"detail,omitempty"`
+}
+
+// Sample is a single RTT observation taken at Timestamp.
+type Sample struct {
+	Timestamp string  `json:"timestamp"`
+	RTTMillis float64 `json:"rtt_ms"`
+}
+
+// History is the rolling record returned by GET /api/device/{ip}/history.
+type History struct {
+	IP            string   `json:"ip"`
+	FirstSeen     string   `json:"first_seen"`
+	LastSeen      string   `json:"last_seen"`
+	Online        bool     `json:"online"`
+	UptimePercent float64  `json:"uptime_percent"`
+	Samples       []Sample `json:"samples"`
+}
+
+type record struct {
+	firstSeen time.Time
+	lastSeen  time.Time
+	online    bool
+	checks    int
+	upChecks  int
+	samples   []Sample
+}
+
+// Monitor periodically re-probes a set of watched devices and tracks
+// their history and online/offline transitions.
+type Monitor struct {
+	probe    ProbeFunc
+	interval time.Duration
+	watcher  linkWatcher
+
+	mu      sync.Mutex
+	records map[string]*record
+
+	subMu       sync.Mutex
+	subscribers map[chan Event]struct{}
+}
+
+// New creates a Monitor that re-probes watched devices every interval
+// using probe.
+func New(probe ProbeFunc, interval time.Duration) *Monitor {
+	return &Monitor{
+		probe:       probe,
+		interval:    interval,
+		watcher:     newLinkWatcher(),
+		records:     make(map[string]*record),
+		subscribers: make(map[chan Event]struct{}),
+	}
+}
+
+// Watch registers ip for periodic re-probing. Calling it for an
+// already-watched IP is a no-op. Once maxWatchedDevices distinct IPs are
+// enrolled, further new IPs are silently ignored rather than growing the
+// background probe loop without bound.
+func (m *Monitor) Watch(ip string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.records[ip]; ok {
+		return
+	}
+	if len(m.records) >= maxWatchedDevices {
+		return
+	}
+	m.records[ip] = &record{}
+}
+
+// Start launches the re-probe ticker and the platform link-change
+// watcher as background goroutines. onLinkChange is invoked whenever a
+// local interface or IP address changes, so the caller can trigger an
+// immediate rescan and re-derive its local IP/subnet. Start returns
+// immediately; cancel ctx to stop both goroutines.
+func (m *Monitor) Start(ctx context.Context, onLinkChange func()) {
+	go m.runProbeLoop(ctx)
+	go m.watcher.Watch(ctx, func() {
+		m.broadcast(Event{
+			Type:      "link_change",
+			Timestamp: time.Now().Format(time.RFC3339),
+		})
+		if onLinkChange != nil {
+			onLinkChange()
+		}
+	})
+}
+
+func (m *Monitor) runProbeLoop(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.probeAll()
+		}
+	}
+}
+
+func (m *Monitor) probeAll() {
+	m.mu.Lock()
+	ips := make([]string, 0, len(m.records))
+	for ip := range m.records {
+		ips = append(ips, ip)
+	}
+	m.mu.Unlock()
+
+	for _, ip := range ips {
+		online, rttMillis := m.probe(ip)
+		m.record(ip, online, rttMillis)
+	}
+}
+
+func (m *Monitor) record(ip string, online bool, rttMillis float64) {
+	now := time.Now()
+
+	m.mu.Lock()
+	rec, ok := m.records[ip]
+	if !ok {
+		rec = &record{}
+		m.records[ip] = rec
+	}
+
+	wasOnline := rec.online
+	if rec.firstSeen.IsZero() && online {
+		rec.firstSeen = now
+	}
+	if online {
+		rec.lastSeen = now
+		rec.samples = append(rec.samples, Sample{
+			Timestamp: now.Format(time.RFC3339),
+			RTTMillis: rttMillis,
+		})
+		if len(rec.samples) > maxSamples {
+			rec.samples = rec.samples[len(rec.samples)-maxSamples:]
+		}
+	}
+	rec.checks++
+	if online {
+		rec.upChecks++
+	}
+	rec.online = online
+	m.mu.Unlock()
+
+	if online != wasOnline {
+		eventType := "device_down"
+		if online {
+			eventType = "device_up"
+		}
+		m.broadcast(Event{
+			Type:      eventType,
+			IP:        ip,
+			Timestamp: now.Format(time.RFC3339),
+		})
+	}
+}
+
+// History returns the rolling history for ip, or ok=false if it isn't
+// being watched.
+func (m *Monitor) History(ip string) (History, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rec, ok := m.records[ip]
+	if !ok {
+		return History{}, false
+	}
+
+	uptime := 0.0
+	if rec.checks > 0 {
+		uptime = 100 * float64(rec.upChecks) / float64(rec.checks)
+	}
+
+	h := History{
+		IP:            ip,
+		Online:        rec.online,
+		UptimePercent: uptime,
+		Samples:       append([]Sample(nil), rec.samples...),
+	}
+	if !rec.firstSeen.IsZero() {
+		h.FirstSeen = rec.firstSeen.Format(time.RFC3339)
+	}
+	if !rec.lastSeen.IsZero() {
+		h.LastSeen = rec.lastSeen.Format(time.RFC3339)
+	}
+	return h, true
+}
+
+// Subscribe returns a channel of events and a cancel func to stop
+// receiving and release it. Callers must drain the channel promptly;
+// a slow subscriber drops events rather than blocking the monitor.
+func (m *Monitor) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, 16)
+
+	m.subMu.Lock()
+	m.subscribers[ch] = struct{}{}
+	m.subMu.Unlock()
+
+	cancel := func() {
+		m.subMu.Lock()
+		delete(m.subscribers, ch)
+		m.subMu.Unlock()
+	}
+	return ch, cancel
+}
+
+func (m *Monitor) broadcast(e Event) {
+	m.subMu.Lock()
+	defer m.subMu.Unlock()
+	for ch := range m.subscribers {
+		select {
+		case ch <- e:
+		default:
+			// Subscriber isn't keeping up; drop rather than block.
+		}
+	}
+}
+
+// linkWatcher abstracts platform-specific local link/address change
+// detection so Monitor.Start doesn't need build tags of its own.
+type linkWatcher interface {
+	Watch(ctx context.Context, onChange func())
+}
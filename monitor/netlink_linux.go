@@ -0,0 +1,64 @@
+//go:build linux
+
+package monitor
+
+import (
+	"context"
+	"sync"
+
+	"golang.org/x/sys/unix"
+)
+
+// netlinkWatcher detects local interface and address changes instantly
+// by subscribing to RTNETLINK link and address multicast groups over
+// an AF_NETLINK/NETLINK_ROUTE socket, instead of polling.
+type netlinkWatcher struct{}
+
+func newLinkWatcher() linkWatcher { return netlinkWatcher{} }
+
+func (netlinkWatcher) Watch(ctx context.Context, onChange func()) {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW, unix.NETLINK_ROUTE)
+	if err != nil {
+		return
+	}
+	var closeOnce sync.Once
+	closeFD := func() { closeOnce.Do(func() { unix.Close(fd) }) }
+	defer closeFD()
+
+	groups := uint32(unix.RTMGRP_LINK | unix.RTMGRP_IPV4_IFADDR | unix.RTMGRP_IPV6_IFADDR)
+	if err := unix.Bind(fd, &unix.SockaddrNetlink{Family: unix.AF_NETLINK, Groups: groups}); err != nil {
+		return
+	}
+
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			closeFD()
+		case <-done:
+		}
+	}()
+	defer close(done)
+
+	// Closing fd does not wake a Recvfrom blocked on it, so a receive
+	// timeout lets the loop notice ctx cancellation on its own.
+	tv := unix.Timeval{Sec: 1}
+	if err := unix.SetsockoptTimeval(fd, unix.SOL_SOCKET, unix.SO_RCVTIMEO, &tv); err != nil {
+		return
+	}
+
+	buf := make([]byte, 4096)
+	for ctx.Err() == nil {
+		n, _, err := unix.Recvfrom(fd, buf, 0)
+		if err == unix.EAGAIN || err == unix.EINTR {
+			continue
+		}
+		if err != nil {
+			return
+		}
+		if n == 0 {
+			continue
+		}
+		onChange()
+	}
+}
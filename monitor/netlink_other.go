@@ -0,0 +1,54 @@
+//go:build !linux
+
+package monitor
+
+import (
+	"context"
+	"net"
+	"sort"
+	"strings"
+	"time"
+)
+
+// pollDiffInterval bounds how quickly non-Linux platforms notice a
+// local interface/address change; there's no portable netlink
+// equivalent to subscribe to, so we diff net.InterfaceAddrs().
+const pollDiffInterval = 3 * time.Second
+
+// pollWatcher is the fallback linkWatcher for platforms without
+// RTNETLINK: it polls net.InterfaceAddrs() and diffs snapshots.
+type pollWatcher struct{}
+
+func newLinkWatcher() linkWatcher { return pollWatcher{} }
+
+func (pollWatcher) Watch(ctx context.Context, onChange func()) {
+	ticker := time.NewTicker(pollDiffInterval)
+	defer ticker.Stop()
+
+	last := snapshotAddrs()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			current := snapshotAddrs()
+			if current != last {
+				last = current
+				onChange()
+			}
+		}
+	}
+}
+
+func snapshotAddrs() string {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return ""
+	}
+	out := make([]string, 0, len(addrs))
+	for _, a := range addrs {
+		out = append(out, a.String())
+	}
+	sort.Strings(out)
+	return strings.Join(out, ",")
+}
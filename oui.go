@@ -0,0 +1,52 @@
+package main
+
+import "strings"
+
+// ouiVendors maps the first three octets of a MAC address (the IEEE
+// Organizationally Unique Identifier) to a vendor name.
+//
+// Known limitation: this is a small, hand-curated sample (a few dozen
+// prefixes for common consumer/infrastructure hardware), not the full
+// IEEE MA-L registry (tens of thousands of entries). vendorForMAC will
+// return "" for the large majority of real-world devices. Swap this for
+// a compacted embed of the actual registry (e.g. via go:embed) if
+// broader vendor coverage is needed.
+var ouiVendors = map[string]string{
+	"00:1A:11": "Google",
+	"3C:5A:B4": "Google",
+	"F4:F5:D8": "Google",
+	"B8:27:EB": "Raspberry Pi Foundation",
+	"DC:A6:32": "Raspberry Pi Foundation",
+	"E4:5F:01": "Raspberry Pi Foundation",
+	"00:17:88": "Philips Hue",
+	"EC:B5:FA": "Apple",
+	"F0:18:98": "Apple",
+	"AC:DE:48": "Apple",
+	"00:1B:63": "Apple",
+	"3C:15:C2": "Apple",
+	"D8:96:95": "Sonos",
+	"00:0E:58": "Sonos",
+	"B0:C5:54": "Sonos",
+	"FC:A1:83": "Amazon",
+	"74:C2:46": "Amazon",
+	"00:50:56": "VMware",
+	"08:00:27": "VirtualBox",
+	"00:1D:D8": "Microsoft",
+	"00:15:5D": "Microsoft Hyper-V",
+	"00:09:0F": "Fortinet",
+	"00:0C:29": "VMware",
+	"F8:0F:41": "Ubiquiti Networks",
+	"24:A4:3C": "Ubiquiti Networks",
+	"00:1C:B3": "Apple",
+}
+
+// vendorForMAC returns the vendor name for a MAC address's OUI prefix,
+// or "" if it isn't in the local table — which, per the limitation
+// noted on ouiVendors, is true for most real-world devices.
+func vendorForMAC(mac string) string {
+	if len(mac) < 8 {
+		return ""
+	}
+	prefix := strings.ToUpper(mac[:8])
+	return ouiVendors[prefix]
+}
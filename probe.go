@@ -0,0 +1,244 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+)
+
+// probeCount is the number of probes sent per device when computing loss/RTT.
+const probeCount = 4
+
+// probeTimeout bounds each individual probe attempt.
+const probeTimeout = 500 * time.Millisecond
+
+// tcpProbePorts is the fallback port sweep used when ICMP is unavailable,
+// e.g. on non-root Windows or networks that block echo requests.
+var tcpProbePorts = []int{22, 80, 443, 445, 3389}
+
+// probeResult carries the outcome of a single reachability probe.
+type probeResult struct {
+	ok     bool
+	rttMS  float64
+	ttl    int
+	method string
+}
+
+func pingDevice(ip string) Device {
+	return pingDeviceTimeout(ip, probeTimeout)
+}
+
+// pingDeviceTimeout is pingDevice with a caller-supplied per-probe
+// timeout, used by scanNetwork so a large CIDR scan can trade accuracy
+// for speed via the ?timeout= query param.
+func pingDeviceTimeout(ip string, timeout time.Duration) Device {
+	device := Device{
+		IP:       ip,
+		Hostname: ip,
+		Status:   "offline",
+		LastSeen: "",
+	}
+
+	results := make([]probeResult, probeCount)
+	var wg sync.WaitGroup
+	for i := 0; i < probeCount; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = probeHost(ip, timeout)
+		}(i)
+	}
+	wg.Wait()
+
+	var (
+		recv     int
+		rttTotal float64
+		ttl      int
+		method   string
+	)
+	for _, r := range results {
+		if !r.ok {
+			continue
+		}
+		recv++
+		rttTotal += r.rttMS
+		if ttl == 0 {
+			ttl = r.ttl
+		}
+		if method == "" {
+			method = r.method
+		}
+	}
+
+	device.LossPercent = 100 * float64(probeCount-recv) / float64(probeCount)
+	device.ProbeMethod = method
+	if device.ProbeMethod == "" {
+		device.ProbeMethod = "none"
+	}
+
+	if recv > 0 {
+		device.Status = "online"
+		device.LastSeen = time.Now().Format(time.RFC3339)
+		device.RTTMillis = rttTotal / float64(recv)
+		device.TTL = ttl
+
+		hostnames, err := net.LookupAddr(ip)
+		if err == nil && len(hostnames) > 0 {
+			device.Hostname = hostnames[0]
+		}
+	}
+
+	return device
+}
+
+// probeHost attempts a single ICMP echo, falling back to a TCP port sweep
+// whenever the ICMP probe fails: when ICMP sockets aren't available
+// (unprivileged processes on most platforms other than Linux with
+// net.ipv4.ping_group_range configured) or when no echo reply arrives
+// within timeout.
+func probeHost(ip string, timeout time.Duration) probeResult {
+	if r, err := icmpProbe(ip, timeout); err == nil {
+		probesTotal.WithLabelValues("icmp", "success").Inc()
+		probeRTTSeconds.Observe(r.rttMS / 1000)
+		return r
+	} else {
+		slog.Debug("icmp probe failed, falling back to tcp sweep", "ip", ip, "error", err)
+		probesTotal.WithLabelValues("icmp", "failure").Inc()
+	}
+	r := tcpProbe(ip, tcpProbePorts, timeout)
+	if r.ok {
+		probesTotal.WithLabelValues("tcp", "success").Inc()
+		probeRTTSeconds.Observe(r.rttMS / 1000)
+	} else {
+		probesTotal.WithLabelValues("tcp", "failure").Inc()
+	}
+	return r
+}
+
+// icmpProbe sends a single ICMP echo request and measures the RTT and
+// reply TTL. It uses an unprivileged "udp" ICMP socket on Linux (requires
+// net.ipv4.ping_group_range to include the process's group) and falls
+// back to a raw socket, which needs CAP_NET_RAW or root.
+func icmpProbe(ip string, timeout time.Duration) (probeResult, error) {
+	conn, err := icmp.ListenPacket("udp4", "0.0.0.0")
+	if err != nil {
+		conn, err = icmp.ListenPacket("ip4:icmp", "0.0.0.0")
+	}
+	if err != nil {
+		return probeResult{}, fmt.Errorf("icmp listen: %w", err)
+	}
+	defer conn.Close()
+
+	dst, err := net.ResolveIPAddr("ip4", ip)
+	if err != nil {
+		return probeResult{}, err
+	}
+
+	msg := icmp.Message{
+		Type: ipv4.ICMPTypeEcho,
+		Code: 0,
+		Body: &icmp.Echo{
+			ID:   os.Getpid() & 0xffff,
+			Seq:  1,
+			Data: []byte("go-network-check"),
+		},
+	}
+	wb, err := msg.Marshal(nil)
+	if err != nil {
+		return probeResult{}, err
+	}
+
+	p4 := conn.IPv4PacketConn()
+	if p4 != nil {
+		_ = p4.SetControlMessage(ipv4.FlagTTL, true)
+	}
+
+	start := time.Now()
+	if _, err := conn.WriteTo(wb, dst); err != nil {
+		return probeResult{}, err
+	}
+	if err := conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return probeResult{}, err
+	}
+
+	rb := make([]byte, 1500)
+	var n int
+	ttl := 0
+	if p4 != nil {
+		var cm *ipv4.ControlMessage
+		n, cm, _, err = p4.ReadFrom(rb)
+		if cm != nil {
+			ttl = cm.TTL
+		}
+	} else {
+		n, _, err = conn.ReadFrom(rb)
+	}
+	if err != nil {
+		return probeResult{}, err
+	}
+	rtt := time.Since(start)
+
+	rm, err := icmp.ParseMessage(1, rb[:n])
+	if err != nil {
+		return probeResult{}, err
+	}
+	if rm.Type != ipv4.ICMPTypeEchoReply {
+		return probeResult{}, fmt.Errorf("unexpected ICMP type %v", rm.Type)
+	}
+
+	return probeResult{
+		ok:     true,
+		rttMS:  float64(rtt.Microseconds()) / 1000.0,
+		ttl:    ttl,
+		method: "icmp",
+	}, nil
+}
+
+// tcpProbe sweeps a short list of commonly open ports concurrently and
+// reports the fastest successful connect as the reachability signal.
+// Many consumer devices (phones, IoT gear, locked-down Windows hosts)
+// never answer ICMP but happily accept a TCP SYN on one of these ports.
+// The ports are dialed in parallel so a dead host costs one timeout,
+// not len(ports) of them.
+func tcpProbe(ip string, ports []int, timeout time.Duration) probeResult {
+	results := make(chan probeResult, len(ports))
+	var wg sync.WaitGroup
+	for _, port := range ports {
+		wg.Add(1)
+		go func(port int) {
+			defer wg.Done()
+			start := time.Now()
+			conn, err := net.DialTimeout("tcp", fmt.Sprintf("%s:%d", ip, port), timeout)
+			if err != nil {
+				results <- probeResult{}
+				return
+			}
+			rtt := time.Since(start)
+			conn.Close()
+			results <- probeResult{
+				ok:     true,
+				rttMS:  float64(rtt.Microseconds()) / 1000.0,
+				method: "tcp",
+			}
+		}(port)
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	best := probeResult{}
+	for r := range results {
+		if r.ok && (!best.ok || r.rttMS < best.rttMS) {
+			best = r
+		}
+	}
+	return best
+}
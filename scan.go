@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"go-network-check/upnp"
+)
+
+// defaultScanWorkers bounds in-flight probes when the caller doesn't
+// specify a worker count, keeping file descriptor usage sane on large
+// CIDRs.
+const defaultScanWorkers = 64
+
+// upnpDiscoveryTimeout bounds the SSDP M-SEARCH window used both by the
+// standalone /api/network/upnp endpoint and the scan merge below.
+const upnpDiscoveryTimeout = 3 * time.Second
+
+// scanNetwork probes every host address in cidr using a bounded worker
+// pool and returns the devices that answered, plus those that did not
+// answer but announced themselves via UPnP (Status "unknown"). workers
+// caps concurrency; timeout bounds each individual probe attempt.
+func scanNetwork(ctx context.Context, cidr string, workers int, timeout time.Duration) ([]Device, error) {
+	start := time.Now()
+
+	stream, err := scanNetworkStream(ctx, cidr, workers, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	devices := []Device{}
+	online := 0
+	for device := range stream {
+		if device.Status == "online" || device.Status == "unknown" {
+			devices = append(devices, device)
+		}
+		if device.Status == "online" {
+			online++
+		}
+	}
+
+	scanDurationSeconds.Observe(time.Since(start).Seconds())
+	devicesOnline.Set(float64(online))
+	lastScanTimestamp.Set(float64(time.Now().Unix()))
+
+	return devices, nil
+}
+
+// scanNetworkStream is like scanNetwork but returns a channel that
+// yields every probed device, online or offline, as soon as its probe
+// completes. The channel is closed once every host has been probed.
+// ctx is checked around every send to jobs/out so that if the caller
+// stops reading (e.g. an HTTP client disconnects) the worker pool and
+// feeder goroutine unwind instead of blocking forever.
+func scanNetworkStream(ctx context.Context, cidr string, workers int, timeout time.Duration) (<-chan Device, error) {
+	ips, err := hostsInCIDR(cidr)
+	if err != nil {
+		return nil, err
+	}
+
+	mdnsServices, err := queryMDNSServices(mdnsSweepTimeout)
+	if err != nil {
+		mdnsServices = nil
+	}
+
+	igdsByHost := upnpDevicesByHost()
+
+	if workers <= 0 {
+		workers = defaultScanWorkers
+	}
+
+	jobs := make(chan string)
+	out := make(chan Device)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ip := range jobs {
+				device := pingDeviceTimeout(ip, timeout)
+				if device.Status == "online" {
+					enrichDevice(&device, mdnsServices)
+					netMonitor.Watch(ip)
+				}
+				if igd, ok := igdsByHost[ip]; ok {
+					device.FriendlyName = igd.FriendlyName
+					device.Model = igd.ModelName
+					if device.Status == "offline" {
+						// Announces itself via UPnP but didn't answer
+						// our active probes - still worth surfacing.
+						device.Status = "unknown"
+					}
+				}
+				select {
+				case out <- device:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for _, ip := range ips {
+			select {
+			case jobs <- ip:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+
+	return out, nil
+}
+
+// upnpDevicesByHost runs an SSDP discovery pass and indexes the results
+// by host IP so scanNetworkStream can label the Internet Gateway Devices
+// it finds with their friendly name and model, even when they don't
+// respond to active probes. upnp.Discover only searches for IGD device
+// types, so other UPnP devices (smart TVs, media renderers) are not
+// labeled. Discovery failures are non-fatal; scanning proceeds without
+// UPnP labels.
+func upnpDevicesByHost() map[string]upnp.Device {
+	igds, err := upnp.Discover(upnpDiscoveryTimeout)
+	if err != nil {
+		return nil
+	}
+
+	byHost := make(map[string]upnp.Device, len(igds))
+	for _, igd := range igds {
+		if host := igd.Host(); host != "" {
+			byHost[host] = igd
+		}
+	}
+	return byHost
+}
+
+// maxScanHosts bounds how many addresses hostsInCIDR will expand in one
+// call. A /16 (65534 usable hosts) is already far more than a single
+// scan sweeps in practice; without this cap a caller-supplied CIDR like
+// /1 would preallocate a multi-gigabyte slice and crash the process.
+const maxScanHosts = 1 << 16
+
+// hostsInCIDR expands cidr into every usable host address, excluding
+// the network and broadcast addresses for blocks larger than a /31.
+func hostsInCIDR(cidr string) ([]string, error) {
+	_, ipnet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CIDR %q: %w", cidr, err)
+	}
+	base4 := ipnet.IP.To4()
+	if base4 == nil {
+		return nil, fmt.Errorf("only IPv4 CIDRs are supported, got %q", cidr)
+	}
+
+	ones, bits := ipnet.Mask.Size()
+	hostBits := uint(bits - ones)
+	// Compute in uint64 first: 1<<32 (the /0 case) overflows uint32 to 0,
+	// which would slip straight past the maxScanHosts check below.
+	count64 := uint64(1) << hostBits
+	if count64 > maxScanHosts {
+		return nil, fmt.Errorf("CIDR %q has %d hosts, exceeding the %d-host scan limit", cidr, count64, maxScanHosts)
+	}
+	count := uint32(count64)
+	start := binary.BigEndian.Uint32(base4)
+
+	skipNetworkAndBroadcast := hostBits > 1
+
+	ips := make([]string, 0, count)
+	for i := uint32(0); i < count; i++ {
+		if skipNetworkAndBroadcast && (i == 0 || i == count-1) {
+			continue
+		}
+		var addr [4]byte
+		binary.BigEndian.PutUint32(addr[:], start+i)
+		ips = append(ips, net.IP(addr[:]).String())
+	}
+	return ips, nil
+}
@@ -0,0 +1,296 @@
+// Package upnp discovers UPnP Internet Gateway Devices on the local
+// network segments via SSDP and queries their external IP address.
+package upnp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	ssdpAddr = "239.255.255.250:1900"
+
+	searchRequest = "M-SEARCH * HTTP/1.1\r\n" +
+		"HOST: 239.255.255.250:1900\r\n" +
+		"MAN: \"ssdp:discover\"\r\n" +
+		"MX: 2\r\n" +
+		"ST: %s\r\n\r\n"
+)
+
+// searchTargets are the device types we ask for; IGDv1 and IGDv2 cover
+// the overwhelming majority of consumer routers.
+var searchTargets = []string{
+	"urn:schemas-upnp-org:device:InternetGatewayDevice:1",
+	"urn:schemas-upnp-org:device:InternetGatewayDevice:2",
+}
+
+// Device describes an Internet Gateway Device discovered via SSDP.
+type Device struct {
+	Location     string `json:"location"`
+	FriendlyName string `json:"friendly_name"`
+	Manufacturer string `json:"manufacturer"`
+	ModelName    string `json:"model_name"`
+	ControlURL   string `json:"control_url,omitempty"`
+	ExternalIP   string `json:"external_ip,omitempty"`
+}
+
+// Host returns the IP address portion of the device's LOCATION URL, or
+// "" if it can't be parsed. Callers use this to correlate an IGD with
+// a host discovered by other means (ping, ARP, mDNS).
+func (d Device) Host() string {
+	u, err := url.Parse(d.Location)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
+
+// Discover sends an SSDP M-SEARCH for InternetGatewayDevice targets on
+// every non-loopback IPv4 interface (a single shared socket would miss
+// replies on multi-homed hosts), fetches each responder's device
+// description, and resolves its WAN connection external IP address.
+func Discover(timeout time.Duration) ([]Device, error) {
+	locations, err := searchLocations(timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	devices := make([]Device, 0, len(locations))
+	for loc := range locations {
+		device, err := describe(loc)
+		if err != nil {
+			continue
+		}
+		if device.ControlURL != "" {
+			if ip, err := getExternalIP(device.ControlURL); err == nil {
+				device.ExternalIP = ip
+			}
+		}
+		devices = append(devices, device)
+	}
+	return devices, nil
+}
+
+// searchLocations sends M-SEARCH requests for each search target on
+// every usable IPv4 interface and returns the set of distinct LOCATION
+// URLs seen in responses within timeout.
+func searchLocations(timeout time.Duration) (map[string]bool, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	locations := map[string]bool{}
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			ipnet, ok := addr.(*net.IPNet)
+			if !ok || ipnet.IP.To4() == nil {
+				continue
+			}
+			for loc := range searchOnInterface(ipnet.IP, timeout) {
+				locations[loc] = true
+			}
+		}
+	}
+	return locations, nil
+}
+
+func searchOnInterface(localIP net.IP, timeout time.Duration) map[string]bool {
+	locations := map[string]bool{}
+
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: localIP, Port: 0})
+	if err != nil {
+		return locations
+	}
+	defer conn.Close()
+
+	dst, err := net.ResolveUDPAddr("udp4", ssdpAddr)
+	if err != nil {
+		return locations
+	}
+
+	for _, st := range searchTargets {
+		msg := fmt.Sprintf(searchRequest, st)
+		conn.WriteToUDP([]byte(msg), dst)
+	}
+
+	deadline := time.Now().Add(timeout)
+	buf := make([]byte, 2048)
+	for {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			break
+		}
+		conn.SetReadDeadline(time.Now().Add(remaining))
+		n, _, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			break
+		}
+		if loc := parseLocation(buf[:n]); loc != "" {
+			locations[loc] = true
+		}
+	}
+	return locations
+}
+
+func parseLocation(resp []byte) string {
+	reader := bufio.NewReader(bytes.NewReader(resp))
+	reader.ReadString('\n') // status line
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			break
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) == 2 && strings.EqualFold(strings.TrimSpace(parts[0]), "LOCATION") {
+			return strings.TrimSpace(parts[1])
+		}
+	}
+	return ""
+}
+
+// descRoot mirrors the subset of the UPnP device description schema we
+// need: identity fields plus a recursive service/device tree to find
+// the WAN connection service.
+type descRoot struct {
+	Device descDevice `xml:"device"`
+}
+
+type descDevice struct {
+	FriendlyName string        `xml:"friendlyName"`
+	Manufacturer string        `xml:"manufacturer"`
+	ModelName    string        `xml:"modelName"`
+	Services     []descService `xml:"serviceList>service"`
+	Devices      []descDevice  `xml:"deviceList>device"`
+}
+
+type descService struct {
+	ServiceType string `xml:"serviceType"`
+	ControlURL  string `xml:"controlURL"`
+}
+
+func describe(location string) (Device, error) {
+	resp, err := http.Get(location)
+	if err != nil {
+		return Device{}, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Device{}, err
+	}
+
+	var root descRoot
+	if err := xml.Unmarshal(body, &root); err != nil {
+		return Device{}, err
+	}
+
+	device := Device{
+		Location:     location,
+		FriendlyName: root.Device.FriendlyName,
+		Manufacturer: root.Device.Manufacturer,
+		ModelName:    root.Device.ModelName,
+	}
+
+	if svc := findWANConnectionService(root.Device); svc != nil {
+		if controlURL, err := resolveURL(location, svc.ControlURL); err == nil {
+			device.ControlURL = controlURL
+		}
+	}
+
+	return device, nil
+}
+
+func findWANConnectionService(d descDevice) *descService {
+	for i := range d.Services {
+		st := d.Services[i].ServiceType
+		if strings.Contains(st, "WANIPConnection") || strings.Contains(st, "WANPPPConnection") {
+			return &d.Services[i]
+		}
+	}
+	for _, child := range d.Devices {
+		if svc := findWANConnectionService(child); svc != nil {
+			return svc
+		}
+	}
+	return nil
+}
+
+func resolveURL(base, ref string) (string, error) {
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	refURL, err := url.Parse(ref)
+	if err != nil {
+		return "", err
+	}
+	return baseURL.ResolveReference(refURL).String(), nil
+}
+
+const getExternalIPSOAPBody = `<?xml version="1.0"?>
+<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/">
+  <s:Body>
+    <u:GetExternalIPAddress xmlns:u="urn:schemas-upnp-org:service:WANIPConnection:1"/>
+  </s:Body>
+</s:Envelope>`
+
+type soapEnvelope struct {
+	Body struct {
+		GetExternalIPAddressResponse struct {
+			NewExternalIPAddress string `xml:"NewExternalIPAddress"`
+		} `xml:"GetExternalIPAddressResponse"`
+	} `xml:"Body"`
+}
+
+// getExternalIP calls the WANIPConnection:GetExternalIPAddress SOAP
+// action against controlURL and returns the router's public IP.
+func getExternalIP(controlURL string) (string, error) {
+	req, err := http.NewRequest(http.MethodPost, controlURL, strings.NewReader(getExternalIPSOAPBody))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", `text/xml; charset="utf-8"`)
+	req.Header.Set("SOAPAction", `"urn:schemas-upnp-org:service:WANIPConnection:1#GetExternalIPAddress"`)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	var env soapEnvelope
+	if err := xml.Unmarshal(body, &env); err != nil {
+		return "", err
+	}
+	ip := env.Body.GetExternalIPAddressResponse.NewExternalIPAddress
+	if ip == "" {
+		return "", fmt.Errorf("no external IP in SOAP response")
+	}
+	return ip, nil
+}